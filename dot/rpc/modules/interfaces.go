@@ -0,0 +1,66 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+)
+
+// NetworkAPI is the interface for the network state methods used by the RPC server.
+type NetworkAPI interface{}
+
+// BlockAPI is the interface for the block state methods used by the RPC server.
+type BlockAPI interface {
+	// BestBlockHash returns the hash of the chain head.
+	BestBlockHash() common.Hash
+	// SubChain returns the canonical chain from start to end, inclusive of
+	// both ends, ordered oldest first.
+	SubChain(start, end common.Hash) ([]common.Hash, error)
+	// GetParentHash returns the hash of bhash's parent block.
+	GetParentHash(bhash common.Hash) (common.Hash, error)
+}
+
+// StorageAPI is the interface for the storage state.
+type StorageAPI interface {
+	// GetStateRootFromBlock resolves bhash to the state root it committed.
+	// A concrete implementation is expected to resolve this through
+	// dot/state.StorageSnapshots in O(log n) without replaying blocks, but
+	// no such implementation exists in this tree yet (See issue #834).
+	GetStateRootFromBlock(bhash *common.Hash) (*common.Hash, error)
+	GetStorage(root *common.Hash, key []byte) ([]byte, error)
+	GetStorageByBlockHash(bhash common.Hash, key []byte) ([]byte, error)
+	Entries(root *common.Hash) (map[string][]byte, error)
+
+	// GetChildStorage returns the value under key in the child trie keyed by
+	// childKey, nested under the ":child_storage:default:" prefix as
+	// Substrate does, at the state rooted at stateRoot.
+	GetChildStorage(stateRoot *common.Hash, childKey, key []byte) ([]byte, error)
+	// ChildEntries returns every key/value pair in the child trie keyed by
+	// childKey, at the state rooted at stateRoot.
+	ChildEntries(stateRoot *common.Hash, childKey []byte) (map[string][]byte, error)
+
+	// RegisterStorageChangeChannel registers ch to receive a
+	// StorageChangeSetResponse for every block the storage trie commits.
+	// The returned id is passed to UnregisterStorageChangeChannel once the
+	// subscriber goes away.
+	RegisterStorageChangeChannel(ch chan<- *StorageChangeSetResponse) (byte, error)
+	// UnregisterStorageChangeChannel removes a channel registered with
+	// RegisterStorageChangeChannel.
+	UnregisterStorageChangeChannel(id byte)
+}
+
+// CoreAPI is the interface for the core methods used by the RPC server.
+type CoreAPI interface {
+	GetMetadata(bhash *common.Hash) ([]byte, error)
+	GetRuntimeVersion(bhash *common.Hash) (*runtime.VersionAPI, error)
+
+	// ExecuteRuntimeCall invokes the exported runtime function named method,
+	// passing data as its raw SCALE-encoded argument, against the state
+	// rooted at stateRoot (the latest state when stateRoot is nil). The
+	// runtime code itself is loaded from that same state, so a call against
+	// a historical stateRoot runs the runtime that was live at that block.
+	// Any storage writes the call makes are discarded once it returns.
+	ExecuteRuntimeCall(stateRoot *common.Hash, method string, data []byte) ([]byte, error)
+}
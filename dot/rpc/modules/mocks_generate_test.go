@@ -0,0 +1,8 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+//go:generate mockgen -destination=mock_storage_test.go -package $GOPACKAGE -source=interfaces.go StorageAPI
+//go:generate mockgen -destination=mock_core_test.go -package $GOPACKAGE -source=interfaces.go CoreAPI
+//go:generate mockgen -destination=mock_block_test.go -package $GOPACKAGE -source=interfaces.go BlockAPI
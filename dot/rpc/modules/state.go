@@ -17,7 +17,10 @@
 package modules
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/ChainSafe/gossamer/lib/common"
@@ -164,25 +167,43 @@ type StateRuntimeVersionResponse struct {
 	Apis             []interface{} `json:"apis"`
 }
 
+// defaultMaxQueryStorageBlocks bounds how many blocks state_queryStorage will
+// walk in a single call, unless overridden with SetMaxQueryStorageBlocks.
+const defaultMaxQueryStorageBlocks = 1000
+
 // StateModule is an RPC module providing access to storage API points.
 type StateModule struct {
-	networkAPI NetworkAPI
-	storageAPI StorageAPI
-	coreAPI    CoreAPI
+	networkAPI            NetworkAPI
+	storageAPI            StorageAPI
+	coreAPI               CoreAPI
+	blockAPI              BlockAPI
+	maxQueryStorageBlocks uint64
 }
 
 // NewStateModule creates a new State module.
-func NewStateModule(net NetworkAPI, storage StorageAPI, core CoreAPI) *StateModule {
+func NewStateModule(net NetworkAPI, storage StorageAPI, core CoreAPI, block BlockAPI) *StateModule {
 	return &StateModule{
-		networkAPI: net,
-		storageAPI: storage,
-		coreAPI:    core,
+		networkAPI:            net,
+		storageAPI:            storage,
+		coreAPI:               core,
+		blockAPI:              block,
+		maxQueryStorageBlocks: defaultMaxQueryStorageBlocks,
 	}
 }
 
+// SetMaxQueryStorageBlocks overrides the state_queryStorage range limit. No
+// node configuration option is wired to it in this tree, so the limit stays at
+// defaultMaxQueryStorageBlocks unless a caller sets it.
+func (sm *StateModule) SetMaxQueryStorageBlocks(max uint64) {
+	sm.maxQueryStorageBlocks = max
+}
+
 // GetPairs returns the keys with prefix, leave empty to get all the keys.
+//  If req.Bhash is set, the keys are read from that block's historical state.
+//  TODO no concrete StorageAPI implementation resolves stateRootHash through
+//  dot/state.StorageSnapshots yet, so req.Bhash isn't actually honored end to
+//  end until one exists (See issue #834)
 func (sm *StateModule) GetPairs(r *http.Request, req *StatePairRequest, res *StatePairResponse) error {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
 	var (
 		stateRootHash *common.Hash
 		err           error
@@ -221,40 +242,144 @@ func (sm *StateModule) GetPairs(r *http.Request, req *StatePairRequest, res *Sta
 	return nil
 }
 
-// Call isn't implemented properly yet.
-func (sm *StateModule) Call(r *http.Request, req *StateCallRequest, res *StateCallResponse) {
-	_ = sm.networkAPI
-	_ = sm.storageAPI
+// Call executes the runtime entry point named in req.Method (e.g.
+//  Core_version, Metadata_metadata, TaggedTransactionQueue_validate_transaction)
+//  against the state at req.Block, or the latest state when req.Block is nil,
+//  passing req.Data as the raw SCALE-encoded argument. Any storage writes the
+//  call makes are discarded, so historical calls never mutate state.
+func (sm *StateModule) Call(r *http.Request, req *StateCallRequest, res *StateCallResponse) error {
+	var (
+		stateRoot *common.Hash
+		err       error
+	)
+
+	if req.Block != nil {
+		stateRoot, err = sm.storageAPI.GetStateRootFromBlock(req.Block)
+		if err != nil {
+			return err
+		}
+	}
+
+	ret, err := sm.coreAPI.ExecuteRuntimeCall(stateRoot, req.Method, req.Data)
+	if err != nil {
+		return err
+	}
+
+	res.StateCallResponse = ret
+	return nil
 }
 
-// GetChildKeys isn't implemented properly yet.
-func (sm *StateModule) GetChildKeys(r *http.Request, req *StateChildStorageRequest, res *StateKeysResponse) {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
+// GetChildKeys returns the keys of the child trie keyed by req.ChildStorageKey,
+//  optionally filtered to those with the prefix carried in req.Key.
+//  If no block hash is provided, the latest state is used.
+func (sm *StateModule) GetChildKeys(r *http.Request, req *StateChildStorageRequest, res *StateKeysResponse) error {
+	stateRoot, err := sm.childStorageStateRoot(req.Block)
+	if err != nil {
+		return err
+	}
+
+	entries, err := sm.storageAPI.ChildEntries(stateRoot, req.ChildStorageKey)
+	if err != nil {
+		return err
+	}
+
+	keys := make([][]byte, 0, len(entries))
+	for k := range entries {
+		kb := []byte(k)
+		if len(req.Key) > 0 && !bytes.HasPrefix(kb, req.Key) {
+			continue
+		}
+		keys = append(keys, kb)
+	}
+
+	*res = keys
+	return nil
+}
+
+// GetChildStorage returns a hex-encoded child trie storage entry under
+//  req.Key in the child trie keyed by req.ChildStorageKey.
+//  If no block hash is provided, the latest state is used.
+func (sm *StateModule) GetChildStorage(r *http.Request, req *StateChildStorageRequest, res *StateStorageDataResponse) error {
+	stateRoot, err := sm.childStorageStateRoot(req.Block)
+	if err != nil {
+		return err
+	}
+
+	item, err := sm.storageAPI.GetChildStorage(stateRoot, req.ChildStorageKey, req.Key)
+	if err != nil {
+		return err
+	}
+
+	*res = StateStorageDataResponse(common.BytesToHex(item))
+	return nil
 }
 
-// GetChildStorage isn't implemented properly yet.
-func (sm *StateModule) GetChildStorage(r *http.Request, req *StateChildStorageRequest, res *StateStorageDataResponse) {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
+// GetChildStorageHash returns the Blake2-256 hash of a child trie storage
+//  entry under req.Key in the child trie keyed by req.ChildStorageKey.
+//  If no block hash is provided, the latest state is used.
+func (sm *StateModule) GetChildStorageHash(r *http.Request, req *StateChildStorageRequest, res *StateChildStorageResponse) error {
+	stateRoot, err := sm.childStorageStateRoot(req.Block)
+	if err != nil {
+		return err
+	}
+
+	item, err := sm.storageAPI.GetChildStorage(stateRoot, req.ChildStorageKey, req.Key)
+	if err != nil {
+		return err
+	}
+
+	if len(item) == 0 {
+		*res = StateChildStorageResponse{}
+		return nil
+	}
+
+	hash, err := common.Blake2bHash(item)
+	if err != nil {
+		return err
+	}
+
+	res.StorageHash = hash.String()
+	return nil
 }
 
-// GetChildStorageHash isn't implemented properly yet.
-func (sm *StateModule) GetChildStorageHash(r *http.Request, req *StateChildStorageRequest, res *StateChildStorageResponse) {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
+// GetChildStorageSize returns the byte size of a child trie storage entry
+//  under req.Key in the child trie keyed by req.ChildStorageKey.
+//  If no block hash is provided, the latest state is used.
+func (sm *StateModule) GetChildStorageSize(r *http.Request, req *StateChildStorageRequest, res *StateChildStorageSizeResponse) error {
+	stateRoot, err := sm.childStorageStateRoot(req.Block)
+	if err != nil {
+		return err
+	}
+
+	item, err := sm.storageAPI.GetChildStorage(stateRoot, req.ChildStorageKey, req.Key)
+	if err != nil {
+		return err
+	}
+
+	res.Size = uint64(len(item))
+	return nil
 }
 
-// GetChildStorageSize isn't implemented properly yet.
-func (sm *StateModule) GetChildStorageSize(r *http.Request, req *StateChildStorageRequest, res *StateChildStorageSizeResponse) {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
+// childStorageStateRoot resolves the state root a child-storage request
+// should read from: the root at bhash, or nil (the latest state) when bhash
+// is nil.
+func (sm *StateModule) childStorageStateRoot(bhash *common.Hash) (*common.Hash, error) {
+	if bhash == nil {
+		return nil, nil
+	}
+	return sm.storageAPI.GetStateRootFromBlock(bhash)
 }
 
 // GetKeys isn't implemented properly yet.
+// TODO implement change storage trie so that block hash parameter works (See issue #834)
 func (sm *StateModule) GetKeys(r *http.Request, req *StateStorageKeyRequest, res *StateStorageKeysResponse) {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
 }
 
-// GetMetadata calls runtime Metadata_metadata function
+// GetMetadata calls runtime Metadata_metadata function at req.Bhash, or the
+//  latest state when req.Bhash is nil.
+//  TODO no concrete StorageAPI/CoreAPI implementation resolves req.Bhash
+//  through dot/state.StorageSnapshots yet (See issue #834)
 func (sm *StateModule) GetMetadata(r *http.Request, req *StateRuntimeMetadataQuery, res *StateMetadataResponse) error {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
 	metadata, err := sm.coreAPI.GetMetadata(req.Bhash)
 	if err != nil {
 		return err
@@ -267,9 +392,24 @@ func (sm *StateModule) GetMetadata(r *http.Request, req *StateRuntimeMetadataQue
 
 // GetRuntimeVersion Get the runtime version at a given block.
 //  If no block hash is provided, the latest version gets returned.
-// TODO currently only returns latest version, add functionality to lookup runtime by block hash (see issue #834)
+//  TODO no concrete CoreAPI implementation resolves bhash through
+//  dot/state.StorageSnapshots yet, so this currently only returns the latest
+//  version regardless of req.Bhash (See issue #834)
 func (sm *StateModule) GetRuntimeVersion(r *http.Request, req *StateRuntimeVersionRequest, res *StateRuntimeVersionResponse) error {
-	rtVersion, err := sm.coreAPI.GetRuntimeVersion(req.Bhash)
+	var bhash *common.Hash
+	if req != nil {
+		bhash = req.Bhash
+	}
+
+	return populateRuntimeVersionResponse(sm.coreAPI, bhash, res)
+}
+
+// populateRuntimeVersionResponse fills res with the runtime version at bhash
+// (the latest version when bhash is nil). It's shared by GetRuntimeVersion
+// and the state_runtimeVersion subscription push, which both need to turn a
+// CoreAPI.GetRuntimeVersion result into the same wire response.
+func populateRuntimeVersionResponse(coreAPI CoreAPI, bhash *common.Hash, res *StateRuntimeVersionResponse) error {
+	rtVersion, err := coreAPI.GetRuntimeVersion(bhash)
 	if err != nil {
 		return err
 	}
@@ -347,7 +487,7 @@ func (sm *StateModule) GetStorageHash(r *http.Request, req *StateStorageHashRequ
 
 // GetStorageSize returns the size of a storage entry at a block's state.
 //  If no block hash is provided, the latest value is used.
-// TODO implement change storage trie so that block hash parameter works (See issue #834)
+//  TODO implement change storage trie so that block hash parameter works (See issue #834)
 func (sm *StateModule) GetStorageSize(r *http.Request, req *StateStorageSizeRequest, res *StateStorageSizeResponse) error {
 	var (
 		item []byte
@@ -377,24 +517,105 @@ func (sm *StateModule) GetStorageSize(r *http.Request, req *StateStorageSizeRequ
 	return nil
 }
 
-// QueryStorage isn't implemented properly yet.
-func (sm *StateModule) QueryStorage(r *http.Request, req *StateStorageQueryRangeRequest, res *StorageChangeSetResponse) error {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
+// QueryStorage implements state_queryStorage: for every block in the
+//  inclusive range [req.StartBlock, req.Block] (head, when req.Block is nil),
+//  it returns the subset of req.Keys whose value changed relative to that
+//  block's parent, as one StorageChangeSetResponse per block that had at
+//  least one change.
+func (sm *StateModule) QueryStorage(r *http.Request, req *StateStorageQueryRangeRequest, res *[]StorageChangeSetResponse) error {
+	if req.StartBlock == nil {
+		return errors.New("the start block of the query range is required")
+	}
+
+	end := sm.blockAPI.BestBlockHash()
+	if req.Block != nil {
+		end = *req.Block
+	}
+
+	chain, err := sm.blockAPI.SubChain(*req.StartBlock, end)
+	if err != nil {
+		return err
+	}
+
+	if uint64(len(chain)) > sm.maxQueryStorageBlocks {
+		return fmt.Errorf("range of %d blocks exceeds the max-query-storage-blocks limit of %d",
+			len(chain), sm.maxQueryStorageBlocks)
+	}
+
+	prev, err := sm.blockAPI.GetParentHash(*req.StartBlock)
+	if err != nil {
+		return err
+	}
+
+	keys := make([][]byte, len(req.Keys))
+	for i, k := range req.Keys {
+		keys[i] = k[:]
+	}
+
+	changeSets := make([]StorageChangeSetResponse, 0, len(chain))
+	for _, block := range chain {
+		var changed []KeyValueOption
+		for _, key := range keys {
+			newVal, err := sm.storageAPI.GetStorageByBlockHash(block, key)
+			if err != nil {
+				return err
+			}
+
+			oldVal, err := sm.storageAPI.GetStorageByBlockHash(prev, key)
+			if err != nil {
+				return err
+			}
+
+			if !bytes.Equal(newVal, oldVal) {
+				changed = append(changed, KeyValueOption{StorageKey: key, StorageData: newVal})
+			}
+		}
+
+		if len(changed) > 0 {
+			blockHash := block
+			changeSets = append(changeSets, StorageChangeSetResponse{Block: &blockHash, Changes: changed})
+		}
+
+		prev = block
+	}
+
+	*res = changeSets
 	return nil
 }
 
-// SubscribeRuntimeVersion isn't implemented properly yet.
-// TODO make this actually a subscription that pushes data
+// SubscribeRuntimeVersion pushes a state_runtimeVersion notification whenever
+//  a newly imported block's changeset touches the :code storage key, so
+//  subscribers learn about runtime upgrades as soon as they land on chain.
+//  If this request didn't arrive over a websocket connection, it falls back
+//  to returning the current runtime version once, the same as GetRuntimeVersion.
 func (sm *StateModule) SubscribeRuntimeVersion(r *http.Request, req *StateStorageQueryRangeRequest, res *StateRuntimeVersionResponse) error {
-	// TODO implement change storage trie so that block hash parameter works (See issue #834)
-	return sm.GetRuntimeVersion(r, nil, res)
+	conn, reg, ok := subscriptionFromContext(r.Context())
+	if !ok {
+		return sm.GetRuntimeVersion(r, nil, res)
+	}
+
+	_, err := reg.subscribeRuntimeVersion(sm.storageAPI, sm.coreAPI, conn)
+	return err
 }
 
 // SubscribeStorage Storage subscription. If storage keys are specified, it creates a message for each block which
 //  changes the specified storage keys. If none are specified, then it creates a message for every block.
-//  This endpoint communicates over the Websocket protocol, but this func should remain here so it's added to rpc_methods list
+//  This endpoint communicates over the Websocket protocol, but this func should remain here so it's added to rpc_methods list.
+//  StartBlock is ignored: a subscription only ever observes blocks imported after the subscribe call, it has no
+//  notion of replaying history.
 func (sm *StateModule) SubscribeStorage(r *http.Request, req *StateStorageQueryRangeRequest, res *StorageChangeSetResponse) error {
-	return nil
+	conn, reg, ok := subscriptionFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+
+	keys := make([][]byte, len(req.Keys))
+	for i, k := range req.Keys {
+		keys[i] = k[:]
+	}
+
+	_, err := reg.subscribeStorage(sm.storageAPI, conn, keys)
+	return err
 }
 
 func convertAPIs(in []*runtime.API_Item) []interface{} {
@@ -0,0 +1,84 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateModule_Call_ResolvesStateRootForBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	coreAPI := NewMockCoreAPI(ctrl)
+
+	block := common.BytesToHash([]byte("block"))
+	stateRoot := common.BytesToHash([]byte("state-root"))
+
+	storageAPI.EXPECT().GetStateRootFromBlock(&block).Return(&stateRoot, nil)
+	coreAPI.EXPECT().ExecuteRuntimeCall(&stateRoot, "Core_version", []byte("data")).Return([]byte("result"), nil)
+
+	sm := NewStateModule(nil, storageAPI, coreAPI, nil)
+
+	req := &StateCallRequest{Method: "Core_version", Data: []byte("data"), Block: &block}
+	res := new(StateCallResponse)
+	err := sm.Call(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Equal(t, []byte("result"), res.StateCallResponse)
+}
+
+func TestStateModule_Call_NilBlockUsesLatestState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	coreAPI := NewMockCoreAPI(ctrl)
+
+	// GetStateRootFromBlock must not be called when req.Block is nil.
+	coreAPI.EXPECT().ExecuteRuntimeCall(nil, "Metadata_metadata", []byte("data")).Return([]byte("result"), nil)
+
+	sm := NewStateModule(nil, storageAPI, coreAPI, nil)
+
+	req := &StateCallRequest{Method: "Metadata_metadata", Data: []byte("data")}
+	res := new(StateCallResponse)
+	err := sm.Call(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Equal(t, []byte("result"), res.StateCallResponse)
+}
+
+func TestStateModule_Call_GetStateRootFromBlockError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	coreAPI := NewMockCoreAPI(ctrl)
+
+	block := common.BytesToHash([]byte("block"))
+	expectedErr := errors.New("no state root indexed for block")
+	storageAPI.EXPECT().GetStateRootFromBlock(&block).Return(nil, expectedErr)
+
+	sm := NewStateModule(nil, storageAPI, coreAPI, nil)
+
+	req := &StateCallRequest{Method: "Core_version", Block: &block}
+	res := new(StateCallResponse)
+	err := sm.Call(&http.Request{}, req, res)
+	require.ErrorIs(t, err, expectedErr)
+}
+
+func TestStateModule_Call_ExecuteRuntimeCallError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	coreAPI := NewMockCoreAPI(ctrl)
+
+	expectedErr := errors.New("runtime trapped")
+	coreAPI.EXPECT().ExecuteRuntimeCall(nil, "Core_version", []byte(nil)).Return(nil, expectedErr)
+
+	sm := NewStateModule(nil, storageAPI, coreAPI, nil)
+
+	req := &StateCallRequest{Method: "Core_version"}
+	res := new(StateCallResponse)
+	err := sm.Call(&http.Request{}, req, res)
+	require.ErrorIs(t, err, expectedErr)
+}
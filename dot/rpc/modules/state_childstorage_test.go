@@ -0,0 +1,89 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateModule_GetChildStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	storageAPI.EXPECT().GetChildStorage(nil, []byte("child"), []byte("key")).Return([]byte("value"), nil)
+
+	sm := NewStateModule(nil, storageAPI, nil, nil)
+
+	req := &StateChildStorageRequest{ChildStorageKey: []byte("child"), Key: []byte("key")}
+	res := new(StateStorageDataResponse)
+	err := sm.GetChildStorage(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Equal(t, StateStorageDataResponse(common.BytesToHex([]byte("value"))), *res)
+}
+
+func TestStateModule_GetChildStorageSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	storageAPI.EXPECT().GetChildStorage(nil, []byte("child"), []byte("key")).Return([]byte("value"), nil)
+
+	sm := NewStateModule(nil, storageAPI, nil, nil)
+
+	req := &StateChildStorageRequest{ChildStorageKey: []byte("child"), Key: []byte("key")}
+	res := new(StateChildStorageSizeResponse)
+	err := sm.GetChildStorageSize(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Equal(t, uint64(len("value")), res.Size)
+}
+
+func TestStateModule_GetChildStorageHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	storageAPI.EXPECT().GetChildStorage(nil, []byte("child"), []byte("key")).Return([]byte("value"), nil)
+
+	sm := NewStateModule(nil, storageAPI, nil, nil)
+
+	req := &StateChildStorageRequest{ChildStorageKey: []byte("child"), Key: []byte("key")}
+	res := new(StateChildStorageResponse)
+	err := sm.GetChildStorageHash(&http.Request{}, req, res)
+	require.NoError(t, err)
+
+	hash, err := common.Blake2bHash([]byte("value"))
+	require.NoError(t, err)
+	require.Equal(t, hash.String(), res.StorageHash)
+}
+
+func TestStateModule_GetChildStorageHash_EmptyValueResetsResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	storageAPI.EXPECT().GetChildStorage(nil, []byte("child"), []byte("key")).Return(nil, nil)
+
+	sm := NewStateModule(nil, storageAPI, nil, nil)
+
+	req := &StateChildStorageRequest{ChildStorageKey: []byte("child"), Key: []byte("key")}
+	res := &StateChildStorageResponse{StorageHash: "stale"}
+	err := sm.GetChildStorageHash(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Equal(t, StateChildStorageResponse{}, *res)
+}
+
+func TestStateModule_GetChildKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	storageAPI.EXPECT().ChildEntries(nil, []byte("child")).Return(map[string][]byte{
+		"foo": []byte("1"),
+		"bar": []byte("2"),
+	}, nil)
+
+	sm := NewStateModule(nil, storageAPI, nil, nil)
+
+	req := &StateChildStorageRequest{ChildStorageKey: []byte("child"), Key: []byte("f")}
+	res := new(StateKeysResponse)
+	err := sm.GetChildKeys(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Equal(t, StateKeysResponse{[]byte("foo")}, *res)
+}
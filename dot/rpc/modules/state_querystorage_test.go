@@ -0,0 +1,65 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateModule_QueryStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	blockAPI := NewMockBlockAPI(ctrl)
+
+	start := common.BytesToHash([]byte("start"))
+	parent := common.BytesToHash([]byte("parent"))
+	block1 := common.BytesToHash([]byte("block1"))
+	block2 := common.BytesToHash([]byte("block2"))
+	key := common.BytesToHash([]byte("key"))
+
+	blockAPI.EXPECT().SubChain(start, start).Return([]common.Hash{block1, block2}, nil)
+	blockAPI.EXPECT().GetParentHash(start).Return(parent, nil)
+
+	// block1 changes key, block2 doesn't.
+	storageAPI.EXPECT().GetStorageByBlockHash(block1, key[:]).Return([]byte("new"), nil)
+	storageAPI.EXPECT().GetStorageByBlockHash(parent, key[:]).Return([]byte("old"), nil)
+	storageAPI.EXPECT().GetStorageByBlockHash(block2, key[:]).Return([]byte("new"), nil)
+	storageAPI.EXPECT().GetStorageByBlockHash(block1, key[:]).Return([]byte("new"), nil)
+
+	sm := NewStateModule(nil, storageAPI, nil, blockAPI)
+
+	req := &StateStorageQueryRangeRequest{Keys: []*common.Hash{&key}, StartBlock: &start, Block: &start}
+	res := new([]StorageChangeSetResponse)
+	err := sm.QueryStorage(&http.Request{}, req, res)
+	require.NoError(t, err)
+	require.Len(t, *res, 1)
+	require.Equal(t, block1, *(*res)[0].Block)
+}
+
+func TestStateModule_QueryStorage_ExceedsMaxBlocks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	blockAPI := NewMockBlockAPI(ctrl)
+
+	start := common.BytesToHash([]byte("start"))
+	end := common.BytesToHash([]byte("end"))
+
+	chain := make([]common.Hash, 5)
+	for i := range chain {
+		chain[i] = common.BytesToHash([]byte{byte(i)})
+	}
+	blockAPI.EXPECT().SubChain(start, end).Return(chain, nil)
+
+	sm := NewStateModule(nil, nil, nil, blockAPI)
+	sm.SetMaxQueryStorageBlocks(2)
+
+	req := &StateStorageQueryRangeRequest{StartBlock: &start, Block: &end}
+	res := new([]StorageChangeSetResponse)
+	err := sm.QueryStorage(&http.Request{}, req, res)
+	require.Error(t, err)
+}
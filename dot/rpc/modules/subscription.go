@@ -0,0 +1,222 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// WSConn is the minimal surface a subscription needs from the websocket
+// connection that accepted the RPC request. A concrete implementation reaches
+// the subscribe methods only through a request context built with
+// WithSubscriptionContext; nothing in this tree calls WithSubscriptionContext,
+// so without such a caller no subscription pushes are sent.
+type WSConn interface {
+	WriteJSON(v interface{}) error
+}
+
+type subscriptionCtxKey string
+
+const (
+	wsConnCtxKey  subscriptionCtxKey = "rpc-ws-conn"
+	subsRegCtxKey subscriptionCtxKey = "rpc-subscription-registry"
+)
+
+// WithSubscriptionContext attaches conn and its SubscriptionRegistry to ctx,
+// so that state_subscribeStorage/state_subscribeRuntimeVersion can register
+// pushes against them when dispatched through that context.
+func WithSubscriptionContext(ctx context.Context, conn WSConn, reg *SubscriptionRegistry) context.Context {
+	ctx = context.WithValue(ctx, wsConnCtxKey, conn)
+	return context.WithValue(ctx, subsRegCtxKey, reg)
+}
+
+func subscriptionFromContext(ctx context.Context) (WSConn, *SubscriptionRegistry, bool) {
+	conn, ok := ctx.Value(wsConnCtxKey).(WSConn)
+	if !ok {
+		return nil, nil, false
+	}
+
+	reg, ok := ctx.Value(subsRegCtxKey).(*SubscriptionRegistry)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return conn, reg, true
+}
+
+// subscriptionNotification is the envelope Substrate JSON-RPC clients expect
+// for subscription pushes, e.g.
+// {"jsonrpc":"2.0","method":"state_storage","params":{"result":...,"subscription":...}}.
+type subscriptionNotification struct {
+	Jsonrpc string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  subscriptionParams `json:"params"`
+}
+
+type subscriptionParams struct {
+	Result       interface{} `json:"result"`
+	Subscription uint32      `json:"subscription"`
+}
+
+// SubscriptionRegistry tracks the live state_subscribeStorage and
+// state_subscribeRuntimeVersion subscriptions for a single websocket
+// connection, keyed by the id Substrate clients echo back on unsubscribe.
+type SubscriptionRegistry struct {
+	mu        sync.Mutex
+	nextID    uint32
+	stopFuncs map[uint32]func()
+}
+
+// NewSubscriptionRegistry creates an empty SubscriptionRegistry.
+func NewSubscriptionRegistry() *SubscriptionRegistry {
+	return &SubscriptionRegistry{stopFuncs: make(map[uint32]func())}
+}
+
+// Unsubscribe stops and removes the subscription with the given id. It is a
+// no-op if id is unknown, e.g. it was already unsubscribed.
+func (r *SubscriptionRegistry) Unsubscribe(id uint32) {
+	r.mu.Lock()
+	stop, ok := r.stopFuncs[id]
+	if ok {
+		delete(r.stopFuncs, id)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		stop()
+	}
+}
+
+func (r *SubscriptionRegistry) register(stop func()) uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	id := r.nextID
+	r.stopFuncs[id] = stop
+	return id
+}
+
+// subscribeStorage registers conn to receive a state_storage notification for
+// every StorageChangeSetResponse whose Changes match keys. An empty keys
+// list matches every block, per the state_subscribeStorage contract.
+func (r *SubscriptionRegistry) subscribeStorage(storageAPI StorageAPI, conn WSConn, keys [][]byte) (uint32, error) {
+	ch := make(chan *StorageChangeSetResponse)
+	chID, err := storageAPI.RegisterStorageChangeChannel(ch)
+	if err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	id := r.register(func() {
+		storageAPI.UnregisterStorageChangeChannel(chID)
+		close(done)
+	})
+
+	go func() {
+		for {
+			select {
+			case change, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				if filtered := filterStorageChanges(change, keys); filtered != nil {
+					if err := conn.WriteJSON(subscriptionNotification{
+						Jsonrpc: "2.0",
+						Method:  "state_storage",
+						Params:  subscriptionParams{Result: filtered, Subscription: id},
+					}); err != nil {
+						r.Unsubscribe(id)
+						return
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return id, nil
+}
+
+// codeStorageKey is the well-known key the runtime code is stored under.
+var codeStorageKey = []byte(":code")
+
+// subscribeRuntimeVersion registers conn to receive a state_runtimeVersion
+// notification whenever a committed block's changeset touches codeStorageKey,
+// i.e. whenever a runtime upgrade lands on chain.
+func (r *SubscriptionRegistry) subscribeRuntimeVersion(storageAPI StorageAPI, coreAPI CoreAPI, conn WSConn) (uint32, error) {
+	ch := make(chan *StorageChangeSetResponse)
+	chID, err := storageAPI.RegisterStorageChangeChannel(ch)
+	if err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	id := r.register(func() {
+		storageAPI.UnregisterStorageChangeChannel(chID)
+		close(done)
+	})
+
+	go func() {
+		for {
+			select {
+			case change, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				if filterStorageChanges(change, [][]byte{codeStorageKey}) == nil {
+					continue
+				}
+
+				res := new(StateRuntimeVersionResponse)
+				if err := populateRuntimeVersionResponse(coreAPI, change.Block, res); err != nil {
+					continue
+				}
+
+				if err := conn.WriteJSON(subscriptionNotification{
+					Jsonrpc: "2.0",
+					Method:  "state_runtimeVersion",
+					Params:  subscriptionParams{Result: res, Subscription: id},
+				}); err != nil {
+					r.Unsubscribe(id)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return id, nil
+}
+
+// filterStorageChanges returns change narrowed to the entries whose
+// StorageKey is in keys, or change unmodified when keys is empty. It returns
+// nil when keys is non-empty and none of change's entries match, meaning no
+// notification should be sent for this block.
+func filterStorageChanges(change *StorageChangeSetResponse, keys [][]byte) *StorageChangeSetResponse {
+	if len(keys) == 0 {
+		return change
+	}
+
+	matched := make([]KeyValueOption, 0, len(change.Changes))
+	for _, kv := range change.Changes {
+		for _, k := range keys {
+			if bytes.Equal(kv.StorageKey, k) {
+				matched = append(matched, kv)
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	return &StorageChangeSetResponse{Block: change.Block, Changes: matched}
+}
@@ -0,0 +1,270 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWSConn records every notification written to it, and can be told to
+// fail the next N writes to simulate a dead client connection.
+type fakeWSConn struct {
+	mu        sync.Mutex
+	written   []subscriptionNotification
+	failNext  int
+	writeErr  error
+	writeChan chan struct{}
+}
+
+func (f *fakeWSConn) WriteJSON(v interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failNext > 0 {
+		f.failNext--
+		if f.writeChan != nil {
+			f.writeChan <- struct{}{}
+		}
+		return f.writeErr
+	}
+
+	f.written = append(f.written, v.(subscriptionNotification))
+	if f.writeChan != nil {
+		f.writeChan <- struct{}{}
+	}
+	return nil
+}
+
+func (f *fakeWSConn) notifications() []subscriptionNotification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]subscriptionNotification, len(f.written))
+	copy(out, f.written)
+	return out
+}
+
+func waitFor(t *testing.T, ch chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for write")
+	}
+}
+
+func TestSubscriptionRegistry_SubscribeStorage_PushesMatchingKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+
+	var ch chan<- *StorageChangeSetResponse
+	storageAPI.EXPECT().RegisterStorageChangeChannel(gomock.Any()).DoAndReturn(
+		func(c chan<- *StorageChangeSetResponse) (byte, error) {
+			ch = c
+			return 1, nil
+		})
+	storageAPI.EXPECT().UnregisterStorageChangeChannel(byte(1))
+
+	reg := NewSubscriptionRegistry()
+	conn := &fakeWSConn{writeChan: make(chan struct{}, 1)}
+
+	id, err := reg.subscribeStorage(storageAPI, conn, [][]byte{[]byte("watched")})
+	require.NoError(t, err)
+
+	block := common.BytesToHash([]byte("block"))
+	ch <- &StorageChangeSetResponse{
+		Block:   &block,
+		Changes: []KeyValueOption{{StorageKey: []byte("watched"), StorageData: []byte("value")}},
+	}
+	waitFor(t, conn.writeChan)
+
+	notifications := conn.notifications()
+	require.Len(t, notifications, 1)
+	require.Equal(t, "state_storage", notifications[0].Method)
+	require.Equal(t, id, notifications[0].Params.Subscription)
+
+	reg.Unsubscribe(id)
+}
+
+func TestSubscriptionRegistry_SubscribeStorage_FiltersNonMatchingKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+
+	var ch chan<- *StorageChangeSetResponse
+	storageAPI.EXPECT().RegisterStorageChangeChannel(gomock.Any()).DoAndReturn(
+		func(c chan<- *StorageChangeSetResponse) (byte, error) {
+			ch = c
+			return 1, nil
+		})
+	storageAPI.EXPECT().UnregisterStorageChangeChannel(byte(1))
+
+	reg := NewSubscriptionRegistry()
+	conn := &fakeWSConn{writeChan: make(chan struct{}, 1)}
+
+	id, err := reg.subscribeStorage(storageAPI, conn, [][]byte{[]byte("watched")})
+	require.NoError(t, err)
+
+	block := common.BytesToHash([]byte("block"))
+	ch <- &StorageChangeSetResponse{
+		Block:   &block,
+		Changes: []KeyValueOption{{StorageKey: []byte("unwatched"), StorageData: []byte("value")}},
+	}
+
+	// Send a matching change afterwards so we can block on its delivery,
+	// proving the unwatched one above was dropped rather than just slow.
+	ch <- &StorageChangeSetResponse{
+		Block:   &block,
+		Changes: []KeyValueOption{{StorageKey: []byte("watched"), StorageData: []byte("value")}},
+	}
+	waitFor(t, conn.writeChan)
+
+	require.Len(t, conn.notifications(), 1)
+
+	reg.Unsubscribe(id)
+}
+
+func TestSubscriptionRegistry_Unsubscribe_StopsDeliveryAndIsSafeTwice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+
+	var ch chan<- *StorageChangeSetResponse
+	storageAPI.EXPECT().RegisterStorageChangeChannel(gomock.Any()).DoAndReturn(
+		func(c chan<- *StorageChangeSetResponse) (byte, error) {
+			ch = c
+			return 1, nil
+		})
+	storageAPI.EXPECT().UnregisterStorageChangeChannel(byte(1))
+
+	reg := NewSubscriptionRegistry()
+	conn := &fakeWSConn{writeChan: make(chan struct{}, 1)}
+
+	id, err := reg.subscribeStorage(storageAPI, conn, nil)
+	require.NoError(t, err)
+
+	reg.Unsubscribe(id)
+	reg.Unsubscribe(id) // must not panic or double-close done
+
+	// The channel still exists (storageAPI doesn't close it), but it has been
+	// unregistered and done is closed, so the subscription goroutine exits
+	// without writing anything.
+	_ = ch
+	require.Empty(t, conn.notifications())
+}
+
+func TestSubscriptionRegistry_SubscribeRuntimeVersion_PushesOnCodeChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	coreAPI := NewMockCoreAPI(ctrl)
+
+	var ch chan<- *StorageChangeSetResponse
+	storageAPI.EXPECT().RegisterStorageChangeChannel(gomock.Any()).DoAndReturn(
+		func(c chan<- *StorageChangeSetResponse) (byte, error) {
+			ch = c
+			return 1, nil
+		})
+	storageAPI.EXPECT().UnregisterStorageChangeChannel(byte(1))
+
+	block := common.BytesToHash([]byte("block"))
+	coreAPI.EXPECT().GetRuntimeVersion(&block).Return(&runtime.VersionAPI{
+		RuntimeVersion: runtime.Version{
+			Spec_name:         []byte("node"),
+			Impl_name:         []byte("node"),
+			Authoring_version: 1,
+			Spec_version:      1,
+			Impl_version:      1,
+		},
+	}, nil)
+
+	reg := NewSubscriptionRegistry()
+	conn := &fakeWSConn{writeChan: make(chan struct{}, 1)}
+
+	id, err := reg.subscribeRuntimeVersion(storageAPI, coreAPI, conn)
+	require.NoError(t, err)
+
+	ch <- &StorageChangeSetResponse{
+		Block:   &block,
+		Changes: []KeyValueOption{{StorageKey: codeStorageKey, StorageData: []byte("new-code")}},
+	}
+	waitFor(t, conn.writeChan)
+
+	notifications := conn.notifications()
+	require.Len(t, notifications, 1)
+	require.Equal(t, "state_runtimeVersion", notifications[0].Method)
+
+	reg.Unsubscribe(id)
+}
+
+func TestSubscriptionRegistry_SubscribeRuntimeVersion_IgnoresUnrelatedKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+	coreAPI := NewMockCoreAPI(ctrl)
+
+	var ch chan<- *StorageChangeSetResponse
+	storageAPI.EXPECT().RegisterStorageChangeChannel(gomock.Any()).DoAndReturn(
+		func(c chan<- *StorageChangeSetResponse) (byte, error) {
+			ch = c
+			return 1, nil
+		})
+	storageAPI.EXPECT().UnregisterStorageChangeChannel(byte(1))
+
+	reg := NewSubscriptionRegistry()
+	conn := &fakeWSConn{writeChan: make(chan struct{}, 1)}
+
+	id, err := reg.subscribeRuntimeVersion(storageAPI, coreAPI, conn)
+	require.NoError(t, err)
+
+	block := common.BytesToHash([]byte("block"))
+	ch <- &StorageChangeSetResponse{
+		Block:   &block,
+		Changes: []KeyValueOption{{StorageKey: []byte("unrelated"), StorageData: []byte("value")}},
+	}
+
+	select {
+	case <-conn.writeChan:
+		t.Fatal("expected no push for a change that didn't touch :code")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	reg.Unsubscribe(id)
+}
+
+func TestSubscriptionRegistry_SubscribeStorage_WriteErrorTearsDownSubscription(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storageAPI := NewMockStorageAPI(ctrl)
+
+	var ch chan<- *StorageChangeSetResponse
+	unregistered := make(chan struct{})
+	storageAPI.EXPECT().RegisterStorageChangeChannel(gomock.Any()).DoAndReturn(
+		func(c chan<- *StorageChangeSetResponse) (byte, error) {
+			ch = c
+			return 1, nil
+		})
+	storageAPI.EXPECT().UnregisterStorageChangeChannel(byte(1)).Do(func(byte) { close(unregistered) })
+
+	reg := NewSubscriptionRegistry()
+	conn := &fakeWSConn{writeErr: errors.New("connection closed"), failNext: 1, writeChan: make(chan struct{}, 1)}
+
+	_, err := reg.subscribeStorage(storageAPI, conn, nil)
+	require.NoError(t, err)
+
+	block := common.BytesToHash([]byte("block"))
+	ch <- &StorageChangeSetResponse{
+		Block:   &block,
+		Changes: []KeyValueOption{{StorageKey: []byte("key"), StorageData: []byte("value")}},
+	}
+	waitFor(t, conn.writeChan)
+
+	select {
+	case <-unregistered:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for subscription teardown after a failed write")
+	}
+}
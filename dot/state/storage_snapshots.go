@@ -0,0 +1,132 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+// PruningMode selects how historical snapshots are retained.
+type PruningMode string
+
+const (
+	// PruningModeArchive retains every snapshot forever.
+	PruningModeArchive PruningMode = "archive"
+	// PruningModeKeepFinalized retains only the last RetainedFinalized
+	// finalized snapshots, pruning older ones as new blocks finalize.
+	PruningModeKeepFinalized PruningMode = "keep-finalized"
+)
+
+// PruningConfig configures the node's historical snapshot retention policy.
+// It's passed to NewStorageSnapshots; no node flag is read into it in this tree.
+type PruningConfig struct {
+	Mode PruningMode
+	// RetainedFinalized is the number of finalized blocks whose snapshots
+	// are kept on disk once Mode is PruningModeKeepFinalized.
+	RetainedFinalized uint32
+}
+
+// DefaultPruningConfig keeps the last 256 finalized snapshots, matching
+// Substrate's default "prune" sync mode.
+var DefaultPruningConfig = PruningConfig{Mode: PruningModeKeepFinalized, RetainedFinalized: 256}
+
+// StorageSnapshots indexes every committed block's state root and its full
+// trie node set, so a block hash resolves to the state root it committed in
+// O(log n) instead of by replaying blocks. It is a backend primitive only:
+// it backs GetStateRootFromBlock, but does not itself implement
+// modules.StorageAPI, and reading a key's value at a historical root still
+// needs a trie reader built on top of this index (See issue #834).
+type StorageSnapshots struct {
+	store  *trie.SnapshotStore
+	config PruningConfig
+
+	mu             sync.RWMutex
+	rootByHash     map[common.Hash]common.Hash
+	nodesByHash    map[common.Hash]map[common.Hash][]byte
+	finalizedOrder []common.Hash // finalized block hashes, oldest first
+}
+
+// NewStorageSnapshots creates a StorageSnapshots backed by store, retaining
+// snapshots according to config.
+func NewStorageSnapshots(store *trie.SnapshotStore, config PruningConfig) *StorageSnapshots {
+	return &StorageSnapshots{
+		store:       store,
+		config:      config,
+		rootByHash:  make(map[common.Hash]common.Hash),
+		nodesByHash: make(map[common.Hash]map[common.Hash][]byte),
+	}
+}
+
+// OnBlockImport indexes the state root committed by importing blockHash, so
+// later reads against blockHash resolve to root. nodes must be the *full*
+// node set of the trie rooted at root (every node reachable from root, not
+// just the ones this block newly wrote) — trie.SnapshotStore.Commit relies
+// on seeing the complete set on every call to refcount nodes shared with an
+// ancestor block correctly; passing only newly-written nodes would
+// under-count references and cause a live sibling/descendant snapshot to be
+// pruned out from under a block that still shares nodes with it. It must be
+// called once per imported block, after the block's state has been
+// committed to the trie.
+func (s *StorageSnapshots) OnBlockImport(blockHash, root common.Hash, nodes map[common.Hash][]byte) error {
+	if err := s.store.Commit(root, nodes); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rootByHash[blockHash] = root
+	s.nodesByHash[blockHash] = nodes
+	return nil
+}
+
+// OnBlockFinalized applies the configured pruning policy now that blockHash
+// is finalized. In PruningModeArchive it's a no-op; in
+// PruningModeKeepFinalized it prunes the oldest finalized snapshot once more
+// than RetainedFinalized are retained.
+func (s *StorageSnapshots) OnBlockFinalized(blockHash common.Hash) error {
+	s.mu.Lock()
+	s.finalizedOrder = append(s.finalizedOrder, blockHash)
+
+	if s.config.Mode != PruningModeKeepFinalized || uint32(len(s.finalizedOrder)) <= s.config.RetainedFinalized {
+		s.mu.Unlock()
+		return nil
+	}
+
+	prune := s.finalizedOrder[0]
+	s.finalizedOrder = s.finalizedOrder[1:]
+	root, ok := s.rootByHash[prune]
+	nodes := s.nodesByHash[prune]
+	delete(s.rootByHash, prune)
+	delete(s.nodesByHash, prune)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return s.store.Prune(root, nodes)
+}
+
+// GetStateRootFromBlock returns the state root committed by blockHash, or
+// nil when blockHash is nil (meaning "use the latest state").
+func (s *StorageSnapshots) GetStateRootFromBlock(blockHash *common.Hash) (*common.Hash, error) {
+	if blockHash == nil {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	root, ok := s.rootByHash[*blockHash]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no state root indexed for block %s", blockHash)
+	}
+
+	return &root, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/require"
+)
+
+type memNodeDB map[common.Hash][]byte
+
+func (m memNodeDB) Get(key []byte) ([]byte, error) {
+	v, ok := m[common.BytesToHash(key)]
+	if !ok {
+		return nil, trie.ErrSnapshotPruned
+	}
+	return v, nil
+}
+
+func (m memNodeDB) Put(key, value []byte) error {
+	m[common.BytesToHash(key)] = value
+	return nil
+}
+
+func (m memNodeDB) Del(key []byte) error {
+	delete(m, common.BytesToHash(key))
+	return nil
+}
+
+// TestStorageSnapshots_SharedNodeSurvivesAncestorPrune asserts that
+// OnBlockImport's full-node-set contract is actually honored end to end:
+// a child block that shares a trie node with its parent must still be able
+// to read that node after the parent's snapshot is pruned.
+func TestStorageSnapshots_SharedNodeSurvivesAncestorPrune(t *testing.T) {
+	db := make(memNodeDB)
+	store := trie.NewSnapshotStore(db)
+	snapshots := NewStorageSnapshots(store, PruningConfig{Mode: PruningModeArchive})
+
+	shared := common.BytesToHash([]byte("shared-node"))
+	onlyInParent := common.BytesToHash([]byte("only-in-parent"))
+	parentBlock := common.BytesToHash([]byte("parent-block"))
+	parentRoot := common.BytesToHash([]byte("parent-root"))
+	childBlock := common.BytesToHash([]byte("child-block"))
+	childRoot := common.BytesToHash([]byte("child-root"))
+
+	// The child's full node set (as OnBlockImport requires) re-references the
+	// shared node rather than omitting it as "not newly written".
+	parentNodes := map[common.Hash][]byte{shared: []byte("shared"), onlyInParent: []byte("parent-only")}
+	childNodes := map[common.Hash][]byte{shared: []byte("shared")}
+
+	require.NoError(t, snapshots.OnBlockImport(parentBlock, parentRoot, parentNodes))
+	require.NoError(t, snapshots.OnBlockImport(childBlock, childRoot, childNodes))
+
+	// Pruning the parent's snapshot must not take the shared node with it.
+	require.NoError(t, store.Prune(parentRoot, parentNodes))
+
+	_, err := store.Get(onlyInParent)
+	require.ErrorIs(t, err, trie.ErrSnapshotPruned)
+
+	v, err := store.Get(shared)
+	require.NoError(t, err)
+	require.Equal(t, []byte("shared"), v)
+
+	root, err := snapshots.GetStateRootFromBlock(&childBlock)
+	require.NoError(t, err)
+	require.Equal(t, childRoot, *root)
+}
+
+// TestStorageSnapshots_OnBlockFinalized_KeepFinalizedPrunesOldest asserts that
+// finalizing more than RetainedFinalized blocks under PruningModeKeepFinalized
+// actually prunes the oldest finalized snapshot's nodes, not just drops it
+// from the bookkeeping maps.
+func TestStorageSnapshots_OnBlockFinalized_KeepFinalizedPrunesOldest(t *testing.T) {
+	db := make(memNodeDB)
+	store := trie.NewSnapshotStore(db)
+	snapshots := NewStorageSnapshots(store, PruningConfig{Mode: PruningModeKeepFinalized, RetainedFinalized: 2})
+
+	blocks := make([]common.Hash, 3)
+	roots := make([]common.Hash, 3)
+	nodes := make([]map[common.Hash][]byte, 3)
+	for i := range blocks {
+		blocks[i] = common.BytesToHash([]byte(fmt.Sprintf("block-%d", i)))
+		roots[i] = common.BytesToHash([]byte(fmt.Sprintf("root-%d", i)))
+		nodes[i] = map[common.Hash][]byte{roots[i]: []byte(fmt.Sprintf("node-%d", i))}
+		require.NoError(t, snapshots.OnBlockImport(blocks[i], roots[i], nodes[i]))
+	}
+
+	// Finalizing blocks 0 and 1 stays within RetainedFinalized=2: nothing pruned yet.
+	require.NoError(t, snapshots.OnBlockFinalized(blocks[0]))
+	require.NoError(t, snapshots.OnBlockFinalized(blocks[1]))
+
+	v, err := store.Get(roots[0])
+	require.NoError(t, err)
+	require.Equal(t, []byte("node-0"), v)
+
+	// Finalizing block 2 pushes the retained count past 2, so block 0's
+	// snapshot — the oldest finalized one — must be pruned.
+	require.NoError(t, snapshots.OnBlockFinalized(blocks[2]))
+
+	_, err = store.Get(roots[0])
+	require.ErrorIs(t, err, trie.ErrSnapshotPruned)
+
+	v, err = store.Get(roots[1])
+	require.NoError(t, err)
+	require.Equal(t, []byte("node-1"), v)
+
+	v, err = store.Get(roots[2])
+	require.NoError(t, err)
+	require.Equal(t, []byte("node-2"), v)
+}
+
+// TestStorageSnapshots_OnBlockFinalized_ArchiveNeverPrunes asserts that
+// PruningModeArchive never prunes a finalized snapshot, regardless of how
+// many blocks are finalized.
+func TestStorageSnapshots_OnBlockFinalized_ArchiveNeverPrunes(t *testing.T) {
+	db := make(memNodeDB)
+	store := trie.NewSnapshotStore(db)
+	snapshots := NewStorageSnapshots(store, PruningConfig{Mode: PruningModeArchive})
+
+	for i := 0; i < 5; i++ {
+		block := common.BytesToHash([]byte(fmt.Sprintf("block-%d", i)))
+		root := common.BytesToHash([]byte(fmt.Sprintf("root-%d", i)))
+		nodes := map[common.Hash][]byte{root: []byte(fmt.Sprintf("node-%d", i))}
+		require.NoError(t, snapshots.OnBlockImport(block, root, nodes))
+		require.NoError(t, snapshots.OnBlockFinalized(block))
+
+		v, err := store.Get(root)
+		require.NoError(t, err)
+		require.Equal(t, []byte(fmt.Sprintf("node-%d", i)), v)
+	}
+}
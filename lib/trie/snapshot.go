@@ -0,0 +1,117 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// NodeDB is the minimal on-disk key/value interface a SnapshotStore persists
+// trie nodes through.
+type NodeDB interface {
+	Get(key []byte) ([]byte, error)
+	Put(key, value []byte) error
+	Del(key []byte) error
+}
+
+// ErrSnapshotPruned is returned to a reader whose requested node was removed
+// by a prune that raced with the read.
+var ErrSnapshotPruned = errors.New("trie: node pruned")
+
+// SnapshotStore persists trie nodes under their Blake2-256 hash, so that
+// nodes shared between the snapshots of successive blocks are written once
+// and kept alive for as long as any snapshot still references them. Each
+// node is reference counted; Prune only removes a node from db once its
+// count reaches zero, i.e. once every snapshot that referenced it has itself
+// been pruned, never merely because a branch stopped being the current head.
+type SnapshotStore struct {
+	db NodeDB
+
+	mu       sync.RWMutex
+	refcount map[common.Hash]uint32
+}
+
+// NewSnapshotStore creates a SnapshotStore persisting nodes through db.
+func NewSnapshotStore(db NodeDB) *SnapshotStore {
+	return &SnapshotStore{
+		db:       db,
+		refcount: make(map[common.Hash]uint32),
+	}
+}
+
+// Commit writes nodes (keyed by their Blake2-256 hash) to db, skipping any
+// node already referenced by an earlier snapshot, and registers root as a
+// new snapshot referencing all of them.
+func (s *SnapshotStore) Commit(root common.Hash, nodes map[common.Hash][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, encoded := range nodes {
+		if s.refcount[hash] == 0 {
+			if err := s.db.Put(hash[:], encoded); err != nil {
+				return err
+			}
+		}
+		s.refcount[hash]++
+	}
+
+	s.refcount[root]++
+	return nil
+}
+
+// Get reads the node with the given hash. The RLock is held across the
+// refcount check and the db.Get call, so a concurrent Prune (which takes the
+// exclusive Lock) can't interleave between them: it either runs entirely
+// before this Get starts, in which case a pruned node is reported as
+// ErrSnapshotPruned, or entirely after this Get returns, in which case Get
+// completes with the node's old contents. Either way Get never observes a
+// node mid-deletion.
+func (s *SnapshotStore) Get(hash common.Hash) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.refcount[hash] == 0 {
+		return nil, ErrSnapshotPruned
+	}
+
+	return s.db.Get(hash[:])
+}
+
+// Prune decrements the refcount of root and of every node in nodes, deleting
+// from db any whose count reaches zero. nodes should be exactly the node set
+// passed to the Commit call that created root.
+func (s *SnapshotStore) Prune(root common.Hash, nodes map[common.Hash][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refcount[root] > 0 {
+		s.refcount[root]--
+		if s.refcount[root] == 0 {
+			delete(s.refcount, root)
+		}
+	}
+
+	for hash := range nodes {
+		count, ok := s.refcount[hash]
+		if !ok || count == 0 {
+			continue
+		}
+
+		count--
+		if count == 0 {
+			delete(s.refcount, hash)
+			if err := s.db.Del(hash[:]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s.refcount[hash] = count
+	}
+
+	return nil
+}
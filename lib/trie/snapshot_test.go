@@ -0,0 +1,136 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/require"
+)
+
+type memNodeDB map[common.Hash][]byte
+
+func (m memNodeDB) Get(key []byte) ([]byte, error) {
+	v, ok := m[common.BytesToHash(key)]
+	if !ok {
+		return nil, ErrSnapshotPruned
+	}
+	return v, nil
+}
+
+func (m memNodeDB) Put(key, value []byte) error {
+	m[common.BytesToHash(key)] = value
+	return nil
+}
+
+func (m memNodeDB) Del(key []byte) error {
+	delete(m, common.BytesToHash(key))
+	return nil
+}
+
+func TestSnapshotStore_SharedNodeSurvivesOneOfTwoPrunes(t *testing.T) {
+	db := make(memNodeDB)
+	store := NewSnapshotStore(db)
+
+	shared := common.BytesToHash([]byte("shared-node"))
+	onlyInA := common.BytesToHash([]byte("only-in-a"))
+	rootA := common.BytesToHash([]byte("root-a"))
+	rootB := common.BytesToHash([]byte("root-b"))
+
+	nodesA := map[common.Hash][]byte{shared: []byte("shared"), onlyInA: []byte("a")}
+	nodesB := map[common.Hash][]byte{shared: []byte("shared")}
+
+	require.NoError(t, store.Commit(rootA, nodesA))
+	require.NoError(t, store.Commit(rootB, nodesB))
+
+	// Pruning snapshot A must not remove the node B still references.
+	require.NoError(t, store.Prune(rootA, nodesA))
+
+	_, err := store.Get(onlyInA)
+	require.ErrorIs(t, err, ErrSnapshotPruned)
+
+	v, err := store.Get(shared)
+	require.NoError(t, err)
+	require.Equal(t, []byte("shared"), v)
+
+	// Pruning snapshot B should now remove the shared node too.
+	require.NoError(t, store.Prune(rootB, nodesB))
+
+	_, err = store.Get(shared)
+	require.ErrorIs(t, err, ErrSnapshotPruned)
+}
+
+// blockingNodeDB wraps a memNodeDB and, on the first Get for a watched key,
+// signals started and waits on resume before actually reading. It lets a
+// test pause a SnapshotStore.Get in the middle of its db.Get call so a
+// concurrent Prune can be raced against it.
+type blockingNodeDB struct {
+	memNodeDB
+	watch   common.Hash
+	started chan struct{}
+	resume  chan struct{}
+}
+
+func (b *blockingNodeDB) Get(key []byte) ([]byte, error) {
+	if common.BytesToHash(key) == b.watch {
+		close(b.started)
+		<-b.resume
+	}
+	return b.memNodeDB.Get(key)
+}
+
+func TestSnapshotStore_GetNeverObservesTornPrune(t *testing.T) {
+	hash := common.BytesToHash([]byte("node"))
+	root := common.BytesToHash([]byte("root"))
+	nodes := map[common.Hash][]byte{hash: []byte("value")}
+
+	db := &blockingNodeDB{
+		memNodeDB: make(memNodeDB),
+		watch:     hash,
+		started:   make(chan struct{}),
+		resume:    make(chan struct{}),
+	}
+	store := NewSnapshotStore(db)
+	require.NoError(t, store.Commit(root, nodes))
+
+	getResult := make(chan struct {
+		value []byte
+		err   error
+	}, 1)
+	go func() {
+		v, err := store.Get(hash)
+		getResult <- struct {
+			value []byte
+			err   error
+		}{v, err}
+	}()
+
+	// Wait until Get is inside db.Get, holding the RLock, then try to prune
+	// concurrently. Prune must block on the exclusive Lock until Get's
+	// RUnlock, so it can only ever run entirely before or entirely after Get
+	// reads the node — never during.
+	<-db.started
+
+	pruneDone := make(chan error, 1)
+	go func() { pruneDone <- store.Prune(root, nodes) }()
+
+	select {
+	case <-pruneDone:
+		t.Fatal("Prune completed while Get still held the RLock inside db.Get")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(db.resume)
+
+	require.NoError(t, <-pruneDone)
+
+	result := <-getResult
+	require.NoError(t, result.err)
+	require.Equal(t, []byte("value"), result.value)
+
+	_, err := store.Get(hash)
+	require.ErrorIs(t, err, ErrSnapshotPruned)
+}